@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runApply handles `migrate apply <plan.json> [flags]`, loading a
+// declarative plan and executing each of its operations in order.
+func runApply(args []string) {
+	if len(args) < 1 || args[0] == "" || args[0][0] == '-' {
+		fmt.Fprintln(os.Stderr, "Error: migrate apply requires a plan file path, e.g. `migrate apply plan.json`.")
+		os.Exit(1)
+	}
+	planPath := args[0]
+
+	fs := flag.NewFlagSet("apply", flag.ExitOnError)
+	connStr := fs.String("conn", "", "PostgreSQL connection string")
+	dryRun := fs.Bool("dry-run", false, "Print SQL and EXPLAIN, do not execute")
+	verbose := fs.Bool("verbose", false, "Verbose logging with timings")
+	fs.Parse(args[1:])
+
+	if *connStr == "" {
+		fmt.Fprintln(os.Stderr, "Error: Missing required -conn flag.")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	plan, err := LoadPlan(planPath)
+	checkFatal(err, "Loading plan")
+
+	fmt.Println("Connecting to database...")
+	db, driver, err := openDB(*connStr)
+	checkFatal(err, "DB connection")
+	defer db.Close()
+
+	err = db.Ping()
+	checkFatal(err, "DB ping")
+
+	err = RunPlan(db, plan, driver, *dryRun, *verbose)
+	checkFatal(err, "Applying plan")
+}
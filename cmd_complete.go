@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runComplete handles `migrate complete`, the second phase of a cutover:
+// it drops the versioned view (all readers must have switched by now),
+// swaps the temp column into place, and tears down the versioned schema.
+func runComplete(args []string) {
+	fs := flag.NewFlagSet("complete", flag.ExitOnError)
+	connStr := fs.String("conn", "", "PostgreSQL connection string")
+	schema := fs.String("schema", "public", "Schema name (default: public)")
+	table := fs.String("table", "", "Table name to migrate")
+	column := fs.String("column", "", "Column name to migrate")
+	name := fs.String("name", "", "Migration name, used for the colmigrate_v<name> schema")
+	dryRun := fs.Bool("dry-run", false, "Print SQL and EXPLAIN, do not execute")
+	verbose := fs.Bool("verbose", false, "Verbose logging with timings")
+	fs.Parse(args)
+
+	if *connStr == "" || *table == "" || *column == "" || *name == "" {
+		fmt.Fprintln(os.Stderr, "Error: Missing required flags (-conn, -table, -column, -name).")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	fmt.Println("Connecting to database...")
+	db, driver, err := openDB(*connStr)
+	checkFatal(err, "DB connection")
+	defer db.Close()
+	checkFatal(db.Ping(), "DB ping")
+
+	op := &ChangeTypeOperation{Schema: *schema, Table: *table, Column: *column, Driver: driver}
+
+	if active, err := findActiveMigration(db, op.schema(), op.Table, op.Column); err == nil && active != nil {
+		op.MigrationID = active.ID
+	}
+
+	checkFatal(dropVersionedView(db, *table, *name, *dryRun, *verbose), "Dropping versioned view")
+	checkFatal(op.Complete(db, *dryRun, *verbose), "Completing migration")
+	checkFatal(dropVersionedSchema(db, *name, *dryRun, *verbose), "Dropping versioned schema")
+
+	fmt.Printf("Migration %q completed.\n", *name)
+}
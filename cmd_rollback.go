@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runRollback handles `migrate rollback`, undoing a `migrate start` that
+// hasn't been completed yet: it drops the versioned view/schema and the
+// temp column/trigger, leaving the table exactly as it was found.
+func runRollback(args []string) {
+	fs := flag.NewFlagSet("rollback", flag.ExitOnError)
+	connStr := fs.String("conn", "", "PostgreSQL connection string")
+	schema := fs.String("schema", "public", "Schema name (default: public)")
+	table := fs.String("table", "", "Table name to migrate")
+	column := fs.String("column", "", "Column name to migrate")
+	name := fs.String("name", "", "Migration name, used for the colmigrate_v<name> schema")
+	dryRun := fs.Bool("dry-run", false, "Print SQL and EXPLAIN, do not execute")
+	verbose := fs.Bool("verbose", false, "Verbose logging with timings")
+	fs.Parse(args)
+
+	if *connStr == "" || *table == "" || *column == "" || *name == "" {
+		fmt.Fprintln(os.Stderr, "Error: Missing required flags (-conn, -table, -column, -name).")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	fmt.Println("Connecting to database...")
+	db, driver, err := openDB(*connStr)
+	checkFatal(err, "DB connection")
+	defer db.Close()
+	checkFatal(db.Ping(), "DB ping")
+
+	op := &ChangeTypeOperation{Schema: *schema, Table: *table, Column: *column, Driver: driver}
+
+	if active, err := findActiveMigration(db, op.schema(), op.Table, op.Column); err == nil && active != nil {
+		op.MigrationID = active.ID
+	}
+
+	checkFatal(dropVersionedView(db, *table, *name, *dryRun, *verbose), "Dropping versioned view")
+	checkFatal(op.Rollback(db, *dryRun, *verbose), "Rolling back migration")
+	checkFatal(dropVersionedSchema(db, *name, *dryRun, *verbose), "Dropping versioned schema")
+
+	if op.MigrationID != 0 && !*dryRun {
+		checkFatal(markMigrationRolledBack(db, op.MigrationID), "Recording rollback")
+	}
+
+	fmt.Printf("Migration %q rolled back.\n", *name)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runStart handles `migrate start`, the first phase of a zero-downtime
+// column-type cutover: it adds the temp column, installs the sync trigger,
+// backfills existing rows, and publishes a versioned view so new readers
+// can opt into the new type before old readers are touched.
+func runStart(args []string) {
+	fs := flag.NewFlagSet("start", flag.ExitOnError)
+	connStr := fs.String("conn", "", "PostgreSQL connection string")
+	schema := fs.String("schema", "public", "Schema name (default: public)")
+	table := fs.String("table", "", "Table name to migrate")
+	column := fs.String("column", "", "Column name to migrate")
+	newType := fs.String("type", "", "New data type (e.g. bigint)")
+	batchSize := fs.Int("batch", 1000, "Batch size for backfill")
+	pkColumn := fs.String("pk", "", "Primary key column for ordered backfill (optional)")
+	up := fs.String("up", "", "SQL expression computing the new column from the row (default: direct copy)")
+	down := fs.String("down", "", "SQL expression computing the old column from the row (default: direct copy)")
+	name := fs.String("name", "", "Migration name, used for the colmigrate_v<name> schema")
+	resume := fs.Bool("resume", false, "Resume an in-progress migration on this column instead of failing")
+	abort := fs.Bool("abort", false, "Roll back an in-progress migration on this column and exit")
+	dryRun := fs.Bool("dry-run", false, "Print SQL and EXPLAIN, do not execute")
+	verbose := fs.Bool("verbose", false, "Verbose logging with timings")
+	fs.Parse(args)
+
+	if *connStr == "" || *table == "" || *column == "" || *newType == "" || *name == "" {
+		fmt.Fprintln(os.Stderr, "Error: Missing required flags (-conn, -table, -column, -type, -name).")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	sanitizedType, err := sanitizeDataType(*newType)
+	checkFatal(err, "Validate new type")
+
+	fmt.Println("Connecting to database...")
+	db, driver, err := openDB(*connStr)
+	checkFatal(err, "DB connection")
+	defer db.Close()
+	checkFatal(db.Ping(), "DB ping")
+
+	op := &ChangeTypeOperation{
+		Schema:    *schema,
+		Table:     *table,
+		Column:    *column,
+		NewType:   sanitizedType,
+		PKColumn:  *pkColumn,
+		BatchSize: *batchSize,
+		Up:        *up,
+		Down:      *down,
+		Driver:    driver,
+	}
+
+	migrationID, shouldContinue, err := resolveMigration(db, op, *name, *resume, *abort, *dryRun, *verbose)
+	checkFatal(err, "Resolving migration state")
+	if !shouldContinue {
+		return
+	}
+	op.MigrationID = migrationID
+
+	checkFatal(op.Preflight(db, *verbose), "Preflight checks")
+	checkFatal(op.Start(db, *dryRun, *verbose), "Starting migration")
+	checkFatal(op.Backfill(db, *dryRun, *verbose), "Backfilling data")
+	checkFatal(createVersionedView(db, *schema, *table, *column, op.tempColumn(), sanitizedType, *name, *dryRun, *verbose),
+		"Creating versioned view")
+
+	fmt.Printf("Migration %q started. New readers can `SET search_path TO %s, %s`.\n", *name, versionedSchema(*name), *schema)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+
+	_ "github.com/lib/pq"
+)
+
+// ExecOpts carries the dry-run/verbose flags every Driver method needs to
+// decide whether to print SQL instead of running it.
+type ExecOpts struct {
+	DryRun  bool
+	Verbose bool
+}
+
+// dbExecutor is the subset of *sql.DB that *sql.Tx also satisfies. Driver
+// methods that run DDL take this instead of *sql.DB so that call site can
+// run them inside a transaction (e.g. to scope SET LOCAL session settings)
+// or directly against the pool, without the driver needing to know which.
+type dbExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Driver isolates the warehouse-specific constructs (ctid pagination,
+// to_regtype, row triggers, ...) behind one interface so the migration
+// steps in ChangeTypeOperation can run against more than just Postgres.
+type Driver interface {
+	ColumnExists(db *sql.DB, schema, table, column string) bool
+	TypeIsValid(db *sql.DB, typeStr string) (bool, error)
+	HasAlter(db *sql.DB, schema, table string) (bool, error)
+	AddColumn(db dbExecutor, schema, table, column, newType string, opts ExecOpts) error
+	InstallSyncTrigger(db dbExecutor, schema, table, column, tempColumn, up, down string, opts ExecOpts) error
+	BackfillBatch(db *sql.DB, schema, table, column, tempColumn, pkColumn, up string, batchSize int, opts ExecOpts) (rowsAffected int64, lastKey string, err error)
+	DropSyncTrigger(db dbExecutor, schema, table, column string, opts ExecOpts) error
+	SwapColumns(db dbExecutor, schema, table, column, tempColumn string, opts ExecOpts) error
+}
+
+// driverRegistry maps a connection string scheme to the Driver that
+// understands it, the same way golang-migrate registers drivers by name.
+var driverRegistry = map[string]Driver{}
+
+// RegisterDriver makes a Driver available under the given connection
+// string scheme (e.g. "postgres", "redshift"). Driver implementations call
+// this from an init().
+func RegisterDriver(scheme string, d Driver) {
+	driverRegistry[scheme] = d
+}
+
+// DriverFor picks a Driver based on the connection string's scheme,
+// defaulting to "postgres" for bare DSNs (e.g. "host=... dbname=...") that
+// have no scheme at all.
+func DriverFor(connStr string) (Driver, error) {
+	scheme := "postgres"
+	if u, err := url.Parse(connStr); err == nil && u.Scheme != "" {
+		scheme = u.Scheme
+	}
+	d, ok := driverRegistry[scheme]
+	if !ok {
+		return nil, fmt.Errorf("no driver registered for scheme %q", scheme)
+	}
+	return d, nil
+}
+
+// openDB resolves the right Driver for connStr and opens a *sql.DB for it.
+// Schemes that speak the Postgres wire protocol but aren't literally
+// "postgres://" (e.g. "redshift://") are rewritten before being handed to
+// lib/pq, which is the only sql.DB driver this binary registers.
+func openDB(connStr string) (*sql.DB, Driver, error) {
+	driver, err := DriverFor(connStr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dsn := connStr
+	if i := strings.Index(dsn, "://"); i >= 0 {
+		dsn = "postgres" + dsn[i:]
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, nil, err
+	}
+	return db, driver, nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// postgresDriver is today's behavior: ctid/pk-paginated backfills and
+// plpgsql row triggers for real-time sync.
+type postgresDriver struct{}
+
+func (postgresDriver) ColumnExists(db *sql.DB, schema, table, column string) bool {
+	return columnExists(db, schema, table, column)
+}
+
+func (postgresDriver) TypeIsValid(db *sql.DB, typeStr string) (bool, error) {
+	return typeIsValid(db, typeStr)
+}
+
+func (postgresDriver) HasAlter(db *sql.DB, schema, table string) (bool, error) {
+	return hasAlterPrivilege(db, schema, table)
+}
+
+func (postgresDriver) AddColumn(db dbExecutor, schema, table, column, newType string, opts ExecOpts) error {
+	if columnExists(db, schema, table, column) {
+		fmt.Println("Temp column already exists, skipping add.")
+		return nil
+	}
+	query := fmt.Sprintf(`ALTER TABLE %s.%s ADD COLUMN %s %s;`,
+		quote(schema), quote(table), quote(column), newType)
+	return execSQLWithOpts(db, query, "Adding new column", opts.DryRun, opts.Verbose)
+}
+
+func (postgresDriver) InstallSyncTrigger(db dbExecutor, schema, table, column, tempColumn, up, down string, opts ExecOpts) error {
+	upFunc, upTrigger := fmt.Sprintf("sync_%s_%s_up", table, column), fmt.Sprintf("trg_sync_%s_%s_up", table, column)
+	downFunc, downTrigger := fmt.Sprintf("sync_%s_%s_down", table, column), fmt.Sprintf("trg_sync_%s_%s_down", table, column)
+
+	upSQL := fmt.Sprintf(`
+		CREATE OR REPLACE FUNCTION %s.%s()
+		RETURNS TRIGGER AS $$
+		BEGIN
+			NEW.%s = (%s);
+			RETURN NEW;
+		END;
+		$$ LANGUAGE plpgsql;
+
+		DROP TRIGGER IF EXISTS %s ON %s.%s;
+
+		CREATE TRIGGER %s
+		BEFORE INSERT OR UPDATE OF %s ON %s.%s
+		FOR EACH ROW EXECUTE FUNCTION %s.%s();
+	`, quote(schema), quote(upFunc),
+		quote(tempColumn), up,
+		quote(upTrigger), quote(schema), quote(table),
+		quote(upTrigger), quote(column), quote(schema), quote(table),
+		quote(schema), quote(upFunc))
+	if err := execSQLWithOpts(db, upSQL, "Creating up-sync trigger", opts.DryRun, opts.Verbose); err != nil {
+		return err
+	}
+
+	downSQL := fmt.Sprintf(`
+		CREATE OR REPLACE FUNCTION %s.%s()
+		RETURNS TRIGGER AS $$
+		BEGIN
+			NEW.%s = (%s);
+			RETURN NEW;
+		END;
+		$$ LANGUAGE plpgsql;
+
+		DROP TRIGGER IF EXISTS %s ON %s.%s;
+
+		CREATE TRIGGER %s
+		BEFORE INSERT OR UPDATE OF %s ON %s.%s
+		FOR EACH ROW EXECUTE FUNCTION %s.%s();
+	`, quote(schema), quote(downFunc),
+		quote(column), down,
+		quote(downTrigger), quote(schema), quote(table),
+		quote(downTrigger), quote(tempColumn), quote(schema), quote(table),
+		quote(schema), quote(downFunc))
+	return execSQLWithOpts(db, downSQL, "Creating down-sync trigger", opts.DryRun, opts.Verbose)
+}
+
+func (postgresDriver) BackfillBatch(db *sql.DB, schema, table, column, tempColumn, pkColumn, up string, batchSize int, opts ExecOpts) (int64, string, error) {
+	var query string
+	if pkColumn != "" {
+		query = fmt.Sprintf(`
+			UPDATE %s.%s AS t
+			SET %s = (%s)
+			FROM (
+				SELECT %s
+				FROM %s.%s
+				WHERE %s IS NOT NULL
+				  AND %s IS NULL
+				ORDER BY %s
+				LIMIT %d
+			) AS s
+			WHERE t.%s = s.%s
+			RETURNING t.%s;
+		`, quote(schema), quote(table),
+			quote(tempColumn), up,
+			quote(pkColumn),
+			quote(schema), quote(table),
+			quote(column), quote(tempColumn),
+			quote(pkColumn), batchSize,
+			quote(pkColumn), quote(pkColumn),
+			quote(pkColumn))
+	} else {
+		query = fmt.Sprintf(`
+			UPDATE %s.%s AS t
+			SET %s = (%s)
+			FROM (
+				SELECT ctid
+				FROM %s.%s
+				WHERE %s IS NOT NULL
+				  AND %s IS NULL
+				LIMIT %d
+			) AS s
+			WHERE t.ctid = s.ctid;
+		`, quote(schema), quote(table),
+			quote(tempColumn), up,
+			quote(schema), quote(table),
+			quote(column), quote(tempColumn), batchSize)
+	}
+
+	if opts.DryRun {
+		fmt.Println("→ Backfill batch (dry-run)")
+		fmt.Println(query)
+		explainQuery(db, query)
+		return 0, "", nil
+	}
+
+	if pkColumn == "" {
+		res, err := db.Exec(query)
+		if err != nil {
+			return 0, "", err
+		}
+		rows, _ := res.RowsAffected()
+		return rows, "", nil
+	}
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return 0, "", err
+	}
+	defer rows.Close()
+
+	var n int64
+	var lastKey string
+	for rows.Next() {
+		if err := rows.Scan(&lastKey); err != nil {
+			return 0, "", err
+		}
+		n++
+	}
+	return n, lastKey, rows.Err()
+}
+
+func (postgresDriver) DropSyncTrigger(db dbExecutor, schema, table, column string, opts ExecOpts) error {
+	upFunc, upTrigger := fmt.Sprintf("sync_%s_%s_up", table, column), fmt.Sprintf("trg_sync_%s_%s_up", table, column)
+	downFunc, downTrigger := fmt.Sprintf("sync_%s_%s_down", table, column), fmt.Sprintf("trg_sync_%s_%s_down", table, column)
+
+	cleanup := fmt.Sprintf(`
+		DROP TRIGGER IF EXISTS %s ON %s.%s;
+		DROP FUNCTION IF EXISTS %s.%s();
+		DROP TRIGGER IF EXISTS %s ON %s.%s;
+		DROP FUNCTION IF EXISTS %s.%s();
+	`, quote(upTrigger), quote(schema), quote(table),
+		quote(schema), quote(upFunc),
+		quote(downTrigger), quote(schema), quote(table),
+		quote(schema), quote(downFunc))
+	return execSQLWithOpts(db, cleanup, "Dropping sync triggers and functions", opts.DryRun, opts.Verbose)
+}
+
+func (postgresDriver) SwapColumns(db dbExecutor, schema, table, column, tempColumn string, opts ExecOpts) error {
+	query := fmt.Sprintf(`
+		ALTER TABLE %s.%s DROP COLUMN %s;
+		ALTER TABLE %s.%s RENAME COLUMN %s TO %s;
+	`, quote(schema), quote(table), quote(column),
+		quote(schema), quote(table), quote(tempColumn), quote(column))
+	return execSQLWithOpts(db, query, "Swapping columns", opts.DryRun, opts.Verbose)
+}
+
+func init() {
+	RegisterDriver("postgres", postgresDriver{})
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// redshiftDriver targets Amazon Redshift, which speaks the Postgres wire
+// protocol but has no row triggers and no ctid. Sync is handled by
+// re-running the backfill to catch rows written since the last pass
+// instead of a trigger, and pagination falls back to a single full-table
+// pass under an exclusive lock instead of ctid batching.
+type redshiftDriver struct{}
+
+func (redshiftDriver) ColumnExists(db *sql.DB, schema, table, column string) bool {
+	return columnExists(db, schema, table, column)
+}
+
+// TypeIsValid has no to_regtype equivalent on Redshift, so it asks the
+// planner to cast a NULL to the requested type instead.
+func (redshiftDriver) TypeIsValid(db *sql.DB, typeStr string) (bool, error) {
+	_, err := db.Exec(fmt.Sprintf(`SELECT NULL::%s;`, typeStr))
+	if err != nil {
+		return false, nil
+	}
+	return true, nil
+}
+
+// HasAlter is not checked on Redshift: privilege grants are cluster-level
+// and has_table_privilege's output doesn't reliably reflect them, so we
+// rely on the ALTER TABLE itself failing loudly if the user lacks access.
+func (redshiftDriver) HasAlter(db *sql.DB, schema, table string) (bool, error) {
+	return true, nil
+}
+
+func (redshiftDriver) AddColumn(db dbExecutor, schema, table, column, newType string, opts ExecOpts) error {
+	if columnExists(db, schema, table, column) {
+		fmt.Println("Temp column already exists, skipping add.")
+		return nil
+	}
+	query := fmt.Sprintf(`ALTER TABLE %s.%s ADD COLUMN %s %s;`,
+		quote(schema), quote(table), quote(column), newType)
+	return execSQLWithOpts(db, query, "Adding new column", opts.DryRun, opts.Verbose)
+}
+
+// InstallSyncTrigger is a no-op: Redshift has no row triggers, so keeping
+// the temp column in sync with concurrent writes is the job of repeated
+// BackfillBatch passes instead.
+func (redshiftDriver) InstallSyncTrigger(db dbExecutor, schema, table, column, tempColumn, up, down string, opts ExecOpts) error {
+	fmt.Println("→ Redshift has no row triggers; sync happens via repeated backfill passes instead.")
+	return nil
+}
+
+// BackfillBatch re-backfills every row still missing a temp-column value
+// in one pass under an exclusive lock, rather than paginating by ctid
+// (which Redshift doesn't expose). Call it repeatedly to converge on zero
+// pending rows the same way the caller's loop already does for Postgres;
+// a continuously running STL_INSERT/STL_UPDATE tailer would close the
+// remaining gap between passes but needs a long-lived background process
+// this CLI doesn't run today.
+func (redshiftDriver) BackfillBatch(db *sql.DB, schema, table, column, tempColumn, pkColumn, up string, batchSize int, opts ExecOpts) (int64, string, error) {
+	query := fmt.Sprintf(`
+		UPDATE %s.%s
+		SET %s = (%s)
+		WHERE %s IS NOT NULL
+		  AND %s IS NULL;
+	`, quote(schema), quote(table),
+		quote(tempColumn), up,
+		quote(column), quote(tempColumn))
+
+	if opts.DryRun {
+		fmt.Println("→ Backfill final pass (dry-run)")
+		fmt.Println(query)
+		return 0, "", nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, "", err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(fmt.Sprintf(`LOCK TABLE %s.%s;`, quote(schema), quote(table))); err != nil {
+		return 0, "", err
+	}
+
+	res, err := tx.Exec(query)
+	if err != nil {
+		return 0, "", err
+	}
+	rows, _ := res.RowsAffected()
+
+	var lastKey string
+	if pkColumn != "" && rows > 0 {
+		maxPKQuery := fmt.Sprintf(`SELECT MAX(%s)::text FROM %s.%s WHERE %s IS NOT NULL;`,
+			quote(pkColumn), quote(schema), quote(table), quote(tempColumn))
+		_ = tx.QueryRow(maxPKQuery).Scan(&lastKey)
+	}
+
+	return rows, lastKey, tx.Commit()
+}
+
+// DropSyncTrigger is a no-op: InstallSyncTrigger never created anything to
+// drop on Redshift.
+func (redshiftDriver) DropSyncTrigger(db dbExecutor, schema, table, column string, opts ExecOpts) error {
+	return nil
+}
+
+func (redshiftDriver) SwapColumns(db dbExecutor, schema, table, column, tempColumn string, opts ExecOpts) error {
+	query := fmt.Sprintf(`
+		ALTER TABLE %s.%s DROP COLUMN %s;
+		ALTER TABLE %s.%s RENAME COLUMN %s TO %s;
+	`, quote(schema), quote(table), quote(column),
+		quote(schema), quote(table), quote(tempColumn), quote(column))
+	return execSQLWithOpts(db, query, "Swapping columns", opts.DryRun, opts.Verbose)
+}
+
+func init() {
+	RegisterDriver("redshift", redshiftDriver{})
+}
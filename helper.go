@@ -9,36 +9,61 @@ import (
 	"time"
 )
 
-// checkFatal is a utility that logs and exits on fatal errors.
+// checkFatal is a utility that logs and exits on fatal errors. It is only
+// safe to call at a CLI entry point (main.go, the cmd_*.go files), where
+// there is no in-flight plan or caller-owned process to leave half done;
+// code reachable from RunPlan or a library entry point like runMigration
+// must return the error instead, so callers can roll back or react.
 func checkFatal(err error, context string) {
 	if err != nil {
 		log.Fatalf("Error [%s]: %v", context, err)
 	}
 }
 
-// execSQL runs an SQL statement and logs it if verbose
-func execSQL(db *sql.DB, query string, context string) {
+// execSQL runs an SQL statement, logging it first, and returns any error
+// instead of exiting so callers on the Operation/Plan path can roll back.
+func execSQL(db dbExecutor, query string, context string) error {
 	fmt.Printf("→ %s...\n", context)
 	_, err := db.Exec(query)
-	checkFatal(err, context)
+	if err != nil {
+		return fmt.Errorf("%s: %w", context, err)
+	}
+	return nil
 }
 
-// execSQLWithOpts runs SQL or prints it when dry-run; prints duration when verbose
-func execSQLWithOpts(db *sql.DB, query string, context string, dryRun bool, verbose bool) {
+// execSQLWithOpts runs SQL or prints it when dry-run; prints duration when
+// verbose. It returns the underlying error rather than exiting so a failed
+// statement can be rolled back by the caller (RunPlan, runMigration) instead
+// of killing the process mid-migration.
+func execSQLWithOpts(db dbExecutor, query string, context string, dryRun bool, verbose bool) error {
 	if dryRun {
 		fmt.Printf("→ %s (dry-run)\n", context)
 		fmt.Println(query)
-		return
+		return nil
 	}
 	if verbose {
 		start := time.Now()
 		fmt.Printf("→ %s...\n", context)
 		_, err := db.Exec(query)
-		checkFatal(err, context)
+		if err != nil {
+			return fmt.Errorf("%s: %w", context, err)
+		}
 		fmt.Printf("  ↳ done in %s\n", time.Since(start))
-		return
+		return nil
 	}
-	execSQL(db, query, context)
+	return execSQL(db, query, context)
+}
+
+// validateExpr fails fast on a malformed up/down SQL fragment by asking
+// Postgres to plan it via EXPLAIN (GENERIC_PLAN) against the target table,
+// without requiring live parameter values or touching any data.
+func validateExpr(db *sql.DB, schema, table, expr string) error {
+	query := fmt.Sprintf(`EXPLAIN (GENERIC_PLAN) SELECT (%s) FROM %s.%s LIMIT 0;`, expr, quote(schema), quote(table))
+	rows, err := db.Query(query)
+	if err != nil {
+		return err
+	}
+	return rows.Close()
 }
 
 // explainQuery prints EXPLAIN output for the given query
@@ -62,8 +87,47 @@ func explainQuery(db *sql.DB, query string) {
 	}
 }
 
+// ColumnInfo describes one column of a table, with Type holding a cast
+// target usable directly in `col::Type` (e.g. "integer", "text[]", or a
+// user-defined enum's own name), not information_schema's "ARRAY"/
+// "USER-DEFINED" placeholders.
+type ColumnInfo struct {
+	Name string
+	Type string
+}
+
+// tableColumns lists a table's columns in ordinal order. It reads from
+// pg_attribute/format_type rather than information_schema.columns because
+// the latter's data_type collapses enums to "USER-DEFINED" and arrays to
+// "ARRAY", neither of which is valid SQL to cast a value to.
+func tableColumns(db *sql.DB, schema, table string) ([]ColumnInfo, error) {
+	rows, err := db.Query(`
+		SELECT a.attname, format_type(a.atttypid, a.atttypmod)
+		FROM pg_attribute a
+		JOIN pg_class c ON c.oid = a.attrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND c.relname = $2
+		  AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY a.attnum
+	`, schema, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cols []ColumnInfo
+	for rows.Next() {
+		var c ColumnInfo
+		if err := rows.Scan(&c.Name, &c.Type); err != nil {
+			return nil, err
+		}
+		cols = append(cols, c)
+	}
+	return cols, rows.Err()
+}
+
 // columnExists checks if a column already exists in the given table
-func columnExists(db *sql.DB, schema, table, column string) bool {
+func columnExists(db dbExecutor, schema, table, column string) bool {
 	var exists bool
 	query := `
 		SELECT EXISTS (
@@ -86,6 +150,12 @@ func quote(identifier string) string {
 	return `"` + identifier + `"`
 }
 
+// quoteLiteral safely wraps a string value in single quotes for use as a
+// SQL literal, e.g. in SET LOCAL or COMMENT ON ... IS.
+func quoteLiteral(value string) string {
+	return `'` + strings.ReplaceAll(value, `'`, `''`) + `'`
+}
+
 // sanitizeDataType validates and normalizes a PostgreSQL type expression
 func sanitizeDataType(input string) (string, error) {
 	s := strings.TrimSpace(input)
@@ -146,56 +216,3 @@ func pendingBackfillCount(db *sql.DB, schema, table, column string) (int64, erro
 	err := db.QueryRow(q).Scan(&n)
 	return n, err
 }
-
-// preflight performs existence checks, privileges, type validity, and shows pending rows
-func preflight(db *sql.DB, schema, table, column, pkColumn, newType string, verbose bool) error {
-	fmt.Println("Running preflight checks...")
-	exists, err := schemaExists(db, schema)
-	if err != nil {
-		return fmt.Errorf("schema check failed: %w", err)
-	}
-	if !exists {
-		return fmt.Errorf("schema %s does not exist", schema)
-	}
-
-	exists, err = tableExists(db, schema, table)
-	if err != nil {
-		return fmt.Errorf("table check failed: %w", err)
-	}
-	if !exists {
-		return fmt.Errorf("table %s.%s does not exist", schema, table)
-	}
-
-	if !columnExists(db, schema, table, column) {
-		return fmt.Errorf("column %s not found on %s.%s", column, schema, table)
-	}
-
-	if pkColumn != "" && !columnExists(db, schema, table, pkColumn) {
-		return fmt.Errorf("pk column %s not found on %s.%s", pkColumn, schema, table)
-	}
-
-	ok, err := hasAlterPrivilege(db, schema, table)
-	if err != nil {
-		return fmt.Errorf("privilege check failed: %w", err)
-	}
-	if !ok {
-		return fmt.Errorf("current user lacks ALTER privilege on %s.%s", schema, table)
-	}
-
-	valid, err := typeIsValid(db, newType)
-	if err != nil {
-		return fmt.Errorf("type check failed: %w", err)
-	}
-	if !valid {
-		return fmt.Errorf("unrecognized type: %s", newType)
-	}
-
-	n, err := pendingBackfillCount(db, schema, table, column)
-	if err == nil {
-		fmt.Printf("Planned rows to backfill: %d\n", n)
-	} else if verbose {
-		fmt.Printf("Could not compute backfill count: %v\n", err)
-	}
-
-	return nil
-}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// MigrationOptions holds the optional, replication-aware behavior a library
+// caller can opt into via the With* functions below. The zero value matches
+// today's CLI behavior exactly.
+type MigrationOptions struct {
+	// SessionSettings are applied with SET LOCAL for the duration of the
+	// Start DDL (add column, install sync trigger) and restored when that
+	// transaction ends, e.g. {"statement_timeout": "5s", "synchronous_commit": "off"}.
+	SessionSettings map[string]string
+	// KickstartReplicationComment, if non-empty, is applied via
+	// COMMENT ON TABLE between Start and Backfill to nudge a stalled
+	// logical replication slot forward without changing any data.
+	KickstartReplicationComment string
+	// BeforeBatch and AfterBatch are called around each backfill batch with
+	// the rows done so far, the total pending at Backfill's start (0 if
+	// unknown), and the last primary key touched (empty without a pk column,
+	// and on the default Postgres path also when the operation has no
+	// MigrationID, as that path reads the pk back only for resumable state).
+	// AfterBatch replaces the
+	// fixed 200ms throttle between batches when set, letting a caller pause
+	// adaptively (e.g. on pg_stat_replication.replay_lag) instead.
+	BeforeBatch func(rowsDone, rowsTotal int64, lastPK string)
+	AfterBatch  func(rowsDone, rowsTotal int64, lastPK string)
+}
+
+// Option configures a MigrationOptions. Pass zero or more to runMigration.
+type Option func(*MigrationOptions)
+
+// WithSettingsOnMigrationStart applies the given session settings for the
+// duration of the Start DDL, mirroring pgroll's option of the same name.
+func WithSettingsOnMigrationStart(settings map[string]string) Option {
+	return func(o *MigrationOptions) {
+		o.SessionSettings = settings
+	}
+}
+
+// WithKickstartReplication inserts a no-op COMMENT ON TABLE between Start
+// and Backfill to advance logical replication slots that have stalled on
+// the preceding DDL.
+func WithKickstartReplication(comment string) Option {
+	return func(o *MigrationOptions) {
+		o.KickstartReplicationComment = comment
+	}
+}
+
+// WithBeforeBatch registers a callback invoked before each backfill batch.
+func WithBeforeBatch(fn func(rowsDone, rowsTotal int64, lastPK string)) Option {
+	return func(o *MigrationOptions) {
+		o.BeforeBatch = fn
+	}
+}
+
+// WithAfterBatch registers a callback invoked after each backfill batch,
+// replacing the default fixed throttle so a caller can pace batches against
+// live replication lag instead.
+func WithAfterBatch(fn func(rowsDone, rowsTotal int64, lastPK string)) Option {
+	return func(o *MigrationOptions) {
+		o.AfterBatch = fn
+	}
+}
+
+// options returns op's MigrationOptions, or the zero value if none were set.
+func (op *ChangeTypeOperation) options() *MigrationOptions {
+	if op.Options == nil {
+		return &MigrationOptions{}
+	}
+	return op.Options
+}
+
+// withSessionSettings runs fn inside a transaction with each of settings
+// applied via SET LOCAL, so they're in effect only for fn's duration and
+// never leak into later statements on the same connection. With no
+// settings, fn just runs directly against db.
+func withSessionSettings(db *sql.DB, settings map[string]string, dryRun bool, fn func(dbExecutor) error) error {
+	if len(settings) == 0 || dryRun {
+		return fn(db)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for name, value := range settings {
+		if _, err := tx.Exec(fmt.Sprintf(`SET LOCAL %s = %s;`, name, quoteLiteral(value))); err != nil {
+			return fmt.Errorf("applying session setting %s: %w", name, err)
+		}
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// kickstartReplication issues a no-op COMMENT ON TABLE to advance logical
+// replication slots that stalled on the preceding DDL, without touching any
+// data.
+func kickstartReplication(db *sql.DB, schema, table, comment string, dryRun, verbose bool) error {
+	query := fmt.Sprintf(`COMMENT ON TABLE %s.%s IS %s;`, quote(schema), quote(table), quoteLiteral(comment))
+	return execSQLWithOpts(db, query, "Kickstarting replication", dryRun, verbose)
+}
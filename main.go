@@ -1,15 +1,28 @@
 package main
 
 import (
-	"database/sql"
 	"flag"
 	"fmt"
 	"os"
-
-	_ "github.com/lib/pq"
 )
 
+// subcommands dispatches to the named subcommand entry points. Anything
+// else falls through to the original single-column flag flow below.
+var subcommands = map[string]func([]string){
+	"apply":    runApply,
+	"start":    runStart,
+	"complete": runComplete,
+	"rollback": runRollback,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			run(os.Args[2:])
+			return
+		}
+	}
+
 	// CLI flags
 	connStr := flag.String("conn", "", "PostgreSQL connection string")
 	schema := flag.String("schema", "public", "Schema name (default: public)")
@@ -18,6 +31,11 @@ func main() {
 	newType := flag.String("type", "", "New data type (e.g. bigint)")
 	batchSize := flag.Int("batch", 1000, "Batch size for backfill")
 	pkColumn := flag.String("pk", "", "Primary key column for ordered backfill (optional)")
+	up := flag.String("up", "", "SQL expression computing the new column from the row (default: direct copy)")
+	down := flag.String("down", "", "SQL expression computing the old column from the row (default: direct copy)")
+	name := flag.String("name", "", "Migration name recorded in column_migrate.migrations (default: <table>_<column>)")
+	resume := flag.Bool("resume", false, "Resume an in-progress migration on this column instead of failing")
+	abort := flag.Bool("abort", false, "Roll back an in-progress migration on this column and exit")
 	dryRun := flag.Bool("dry-run", false, "Print SQL and EXPLAIN, do not execute")
 	verbose := flag.Bool("verbose", false, "Verbose logging with timings")
 
@@ -36,7 +54,7 @@ func main() {
 
 	// Connect to the DB
 	fmt.Println("Connecting to database...")
-	db, err := sql.Open("postgres", *connStr)
+	db, driver, err := openDB(*connStr)
 	checkFatal(err, "DB connection")
 
 	defer db.Close()
@@ -44,11 +62,35 @@ func main() {
 	err = db.Ping()
 	checkFatal(err, "DB ping")
 
+	op := &ChangeTypeOperation{
+		Schema:    *schema,
+		Table:     *table,
+		Column:    *column,
+		NewType:   sanitizedType,
+		PKColumn:  *pkColumn,
+		BatchSize: *batchSize,
+		Up:        *up,
+		Down:      *down,
+		Driver:    driver,
+	}
+
+	migrationName := *name
+	if migrationName == "" {
+		migrationName = fmt.Sprintf("%s_%s", *table, *column)
+	}
+
+	migrationID, shouldContinue, err := resolveMigration(db, op, migrationName, *resume, *abort, *dryRun, *verbose)
+	checkFatal(err, "Resolving migration state")
+	if !shouldContinue {
+		return
+	}
+	op.MigrationID = migrationID
+
 	// Preflight checks
-	err = preflight(db, *schema, *table, *column, *pkColumn, sanitizedType, *verbose)
+	err = op.Preflight(db, *verbose)
 	checkFatal(err, "Preflight checks")
 
 	// Start migration
-	err = runMigration(db, *schema, *table, *column, sanitizedType, *batchSize, *pkColumn, *dryRun, *verbose)
+	err = runMigration(db, op, *dryRun, *verbose)
 	checkFatal(err, "Migration process")
 }
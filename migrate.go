@@ -6,54 +6,293 @@ import (
 	"time"
 )
 
-func runMigration(db *sql.DB, schema, table, column, newType string, batchSize int, pkColumn string, dryRun bool, verbose bool) error {
-	tempColumn := column + "_new"
-	funcName := fmt.Sprintf("sync_%s_%s", table, column)
-	triggerName := fmt.Sprintf("trg_sync_%s_%s", table, column)
-
-	// Step 1: Add temp column
-	if !columnExists(db, schema, table, tempColumn) {
-		query := fmt.Sprintf(
-			`ALTER TABLE %s.%s ADD COLUMN %s %s;`,
-			quote(schema), quote(table), quote(tempColumn), newType,
-		)
-		execSQLWithOpts(db, query, "Adding new column", dryRun, verbose)
-	} else {
-		fmt.Println("Temp column already exists, skipping add.")
+// ChangeTypeOperation widens or otherwise converts the type of an existing
+// column via an add-temp-column / sync-trigger / backfill / swap sequence.
+// It is the Operation backing both the legacy `-table/-column/-type` CLI
+// flags and the "change_type" plan operation.
+type ChangeTypeOperation struct {
+	Schema    string `json:"schema"`
+	Table     string `json:"table"`
+	Column    string `json:"column"`
+	NewType   string `json:"new_type"`
+	PKColumn  string `json:"pk"`
+	BatchSize int    `json:"batch"`
+	// Up converts a value of the old column into the new type, e.g.
+	// "UPPER(name)::varchar(255)". Defaults to a plain column reference,
+	// i.e. a direct assignment-compatible copy.
+	Up string `json:"up"`
+	// Down converts a value of the new column back into the old type, kept
+	// in sync so writes via either schema version stay consistent. Defaults
+	// to a plain column reference.
+	Down string `json:"down"`
+
+	// MigrationID, when set, is the column_migrate.migrations row this
+	// operation is resuming or recording progress against. Plan-driven
+	// operations get it from resolvePlanMigrationState in plan.go.
+	MigrationID int64 `json:"-"`
+
+	// Driver picks the warehouse this operation targets. Defaults to
+	// postgresDriver, today's behavior, when left nil.
+	Driver Driver `json:"-"`
+
+	// Options carries replication-aware behavior (session settings during
+	// DDL, a replication kickstart, batch hooks) set via the With* functions
+	// in hooks.go. Left nil, every op behaves exactly as before.
+	Options *MigrationOptions `json:"-"`
+}
+
+func (op *ChangeTypeOperation) driver() Driver {
+	if op.Driver != nil {
+		return op.Driver
+	}
+	return postgresDriver{}
+}
+
+func (op *ChangeTypeOperation) schema() string {
+	if op.Schema == "" {
+		return "public"
+	}
+	return op.Schema
+}
+
+func (op *ChangeTypeOperation) batchSize() int {
+	if op.BatchSize <= 0 {
+		return 1000
+	}
+	return op.BatchSize
+}
+
+func (op *ChangeTypeOperation) tempColumn() string {
+	return op.Column + "_new"
+}
+
+func (op *ChangeTypeOperation) funcName() string {
+	return fmt.Sprintf("sync_%s_%s_up", op.Table, op.Column)
+}
+
+func (op *ChangeTypeOperation) triggerName() string {
+	return fmt.Sprintf("trg_sync_%s_%s_up", op.Table, op.Column)
+}
+
+func (op *ChangeTypeOperation) downFuncName() string {
+	return fmt.Sprintf("sync_%s_%s_down", op.Table, op.Column)
+}
+
+func (op *ChangeTypeOperation) downTriggerName() string {
+	return fmt.Sprintf("trg_sync_%s_%s_down", op.Table, op.Column)
+}
+
+// upExpr returns the SQL fragment that computes the new column's value from
+// the row, defaulting to a plain copy of the old column.
+func (op *ChangeTypeOperation) upExpr() string {
+	if op.Up != "" {
+		return op.Up
+	}
+	return quote(op.Column)
+}
+
+// downExpr returns the SQL fragment that computes the old column's value
+// from the row, defaulting to a plain copy of the new column.
+func (op *ChangeTypeOperation) downExpr() string {
+	if op.Down != "" {
+		return op.Down
+	}
+	return quote(op.tempColumn())
+}
+
+// Preflight runs the existence/privilege/type checks shared by every entry
+// point into a column-type change, plus (on Postgres) an EXPLAIN
+// (GENERIC_PLAN) of the up/down expressions so a typo in either fails
+// before anything is changed.
+func (op *ChangeTypeOperation) Preflight(db *sql.DB, verbose bool) error {
+	schema, table, driver := op.schema(), op.Table, op.driver()
+
+	exists, err := schemaExists(db, schema)
+	if err != nil {
+		return fmt.Errorf("schema check failed: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("schema %s does not exist", schema)
+	}
+
+	exists, err = tableExists(db, schema, table)
+	if err != nil {
+		return fmt.Errorf("table check failed: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("table %s.%s does not exist", schema, table)
+	}
+
+	if !driver.ColumnExists(db, schema, table, op.Column) {
+		return fmt.Errorf("column %s not found on %s.%s", op.Column, schema, table)
+	}
+
+	if op.PKColumn != "" && !driver.ColumnExists(db, schema, table, op.PKColumn) {
+		return fmt.Errorf("pk column %s not found on %s.%s", op.PKColumn, schema, table)
+	}
+
+	ok, err := driver.HasAlter(db, schema, table)
+	if err != nil {
+		return fmt.Errorf("privilege check failed: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("current user lacks ALTER privilege on %s.%s", schema, table)
+	}
+
+	valid, err := driver.TypeIsValid(db, op.NewType)
+	if err != nil {
+		return fmt.Errorf("type check failed: %w", err)
+	}
+	if !valid {
+		return fmt.Errorf("unrecognized type: %s", op.NewType)
+	}
+
+	if _, ok := driver.(postgresDriver); ok {
+		if err := validateExpr(db, schema, table, op.upExpr()); err != nil {
+			return fmt.Errorf("invalid up expression: %w", err)
+		}
+		// The down expression may read the temp column, which a fresh run
+		// hasn't added yet, so plan it against a row shape that includes it.
+		if driver.ColumnExists(db, schema, table, op.tempColumn()) {
+			if err := validateExpr(db, schema, table, op.downExpr()); err != nil {
+				return fmt.Errorf("invalid down expression: %w", err)
+			}
+		} else {
+			query := fmt.Sprintf(`EXPLAIN (GENERIC_PLAN) SELECT (%s) FROM (SELECT *, NULL::%s AS %s FROM %s.%s) AS %s LIMIT 0;`,
+				op.downExpr(), op.NewType, quote(op.tempColumn()), quote(schema), quote(table), quote(table))
+			rows, err := db.Query(query)
+			if err != nil {
+				return fmt.Errorf("invalid down expression: %w", err)
+			}
+			rows.Close()
+		}
+	}
+
+	n, err := pendingBackfillCount(db, schema, table, op.Column)
+	if err == nil {
+		fmt.Printf("Planned rows to backfill: %d\n", n)
+	} else if verbose {
+		fmt.Printf("Could not compute backfill count: %v\n", err)
+	}
+
+	return nil
+}
+
+// Start adds the temp column and installs whatever the driver uses to keep
+// it in lockstep with the original column for rows written after this
+// point (a pair of sync triggers on Postgres; nothing on warehouses
+// without row triggers, which instead rely on repeated Backfill passes).
+// Any SessionSettings are applied with SET LOCAL for this DDL's transaction
+// only, and a replication kickstart, if requested, runs once it commits.
+func (op *ChangeTypeOperation) Start(db *sql.DB, dryRun, verbose bool) error {
+	opts := ExecOpts{DryRun: dryRun, Verbose: verbose}
+	driver := op.driver()
+	settings := op.options().SessionSettings
+
+	err := withSessionSettings(db, settings, dryRun, func(exec dbExecutor) error {
+		if err := driver.AddColumn(exec, op.schema(), op.Table, op.tempColumn(), op.NewType, opts); err != nil {
+			return err
+		}
+		return driver.InstallSyncTrigger(exec, op.schema(), op.Table, op.Column, op.tempColumn(), op.upExpr(), op.downExpr(), opts)
+	})
+	if err != nil {
+		return err
+	}
+
+	if comment := op.options().KickstartReplicationComment; comment != "" && !dryRun {
+		return kickstartReplication(db, op.schema(), op.Table, comment, dryRun, verbose)
+	}
+	return nil
+}
+
+// Backfill copies the original column's values into the temp column for
+// rows that existed before Start ran. The default Postgres path paginates
+// by pk (or ctid) and, when resumable state is in play, checkpoints
+// last_pk/rows_done in the same transaction as each batch's UPDATE; other
+// drivers run their own BackfillBatch strategy in a plain loop until no
+// rows are left pending.
+func (op *ChangeTypeOperation) Backfill(db *sql.DB, dryRun, verbose bool) error {
+	if _, ok := op.driver().(postgresDriver); !ok {
+		return op.backfillWithDriver(db, dryRun, verbose)
 	}
+	return op.backfillPostgres(db, dryRun, verbose)
+}
+
+// backfillWithDriver runs the generic Driver.BackfillBatch loop used by
+// every non-default driver.
+func (op *ChangeTypeOperation) backfillWithDriver(db *sql.DB, dryRun, verbose bool) error {
+	driver := op.driver()
+	opts := ExecOpts{DryRun: dryRun, Verbose: verbose}
+	hooks := op.options()
+	rowsTotal, _ := pendingBackfillCount(db, op.schema(), op.Table, op.Column)
 
-	// Step 2: Create sync trigger
-	triggerSQL := fmt.Sprintf(`
-		CREATE OR REPLACE FUNCTION %s.%s()
-		RETURNS TRIGGER AS $$
-		BEGIN
-			NEW.%s = NEW.%s;
-			RETURN NEW;
-		END;
-		$$ LANGUAGE plpgsql;
+	fmt.Println("→ Backfilling data...")
+	var rowsDone int64
+	var lastKey string
+	for {
+		if hooks.BeforeBatch != nil {
+			hooks.BeforeBatch(rowsDone, rowsTotal, lastKey)
+		}
+
+		start := time.Now()
+		rows, newLastKey, err := driver.BackfillBatch(db, op.schema(), op.Table, op.Column, op.tempColumn(), op.PKColumn, op.upExpr(), op.batchSize(), opts)
+		if err != nil {
+			return err
+		}
+		rowsDone += rows
+		lastKey = newLastKey
 
-		DROP TRIGGER IF EXISTS %s ON %s.%s;
+		if verbose {
+			fmt.Printf("  ↳ Batch updated %d rows in %s\n", rows, time.Since(start))
+		} else if rows > 0 {
+			fmt.Printf("  ↳ Backfilled %d rows...\n", rows)
+		}
+		if op.MigrationID != 0 && !dryRun {
+			if err := checkpointRowsDoneStandalone(db, op.MigrationID, lastKey, rowsDone); err != nil {
+				return fmt.Errorf("checkpointing progress: %w", err)
+			}
+		}
 
-		CREATE TRIGGER %s
-		BEFORE INSERT OR UPDATE ON %s.%s
-		FOR EACH ROW EXECUTE FUNCTION %s.%s();
-	`, quote(schema), quote(funcName),
-		quote(tempColumn), quote(column),
-		quote(triggerName), quote(schema), quote(table),
-		quote(triggerName), quote(schema), quote(table),
-		quote(schema), quote(funcName))
+		if hooks.AfterBatch != nil {
+			hooks.AfterBatch(rowsDone, rowsTotal, lastKey)
+		}
+		if dryRun || rows == 0 {
+			return nil
+		}
+		if hooks.AfterBatch == nil {
+			time.Sleep(200 * time.Millisecond) // throttle
+		}
+	}
+}
 
-	execSQLWithOpts(db, triggerSQL, "Creating trigger for real-time sync", dryRun, verbose)
+// backfillPostgres is the original pk/ctid-paginated batch loop, kept as
+// its own method so the transactional checkpointing it does for resumable
+// migrations (see runBackfillBatch) isn't disturbed by the driver
+// abstraction used for other warehouses.
+func (op *ChangeTypeOperation) backfillPostgres(db *sql.DB, dryRun, verbose bool) error {
+	schema, table := op.schema(), op.Table
+	tempColumn := op.tempColumn()
+	batchSize := op.batchSize()
+	hooks := op.options()
+	rowsTotal, _ := pendingBackfillCount(db, schema, table, op.Column)
 
-	// Step 3: Backfill in batches
 	fmt.Println("→ Backfilling data in batches...")
+	var rowsDone int64
+	var lastPK string
 	for {
+		if hooks.BeforeBatch != nil {
+			hooks.BeforeBatch(rowsDone, rowsTotal, lastPK)
+		}
+
 		var query string
-		if pkColumn != "" {
-			// Deterministic batches by primary key
+		returning := ""
+		if op.MigrationID != 0 && op.PKColumn != "" {
+			returning = fmt.Sprintf(" RETURNING t.%s", quote(op.PKColumn))
+		}
+		if op.PKColumn != "" {
 			query = fmt.Sprintf(`
 				UPDATE %s.%s AS t
-				SET %s = t.%s
+				SET %s = (%s)
 				FROM (
 					SELECT %s
 					FROM %s.%s
@@ -62,19 +301,18 @@ func runMigration(db *sql.DB, schema, table, column, newType string, batchSize i
 					ORDER BY %s
 					LIMIT %d
 				) AS s
-				WHERE t.%s = s.%s;
+				WHERE t.%s = s.%s%s;
 			`, quote(schema), quote(table),
-				quote(tempColumn), quote(column),
-				quote(pkColumn),
+				quote(tempColumn), op.upExpr(),
+				quote(op.PKColumn),
 				quote(schema), quote(table),
-				quote(column), quote(tempColumn),
-				quote(pkColumn), batchSize,
-				quote(pkColumn), quote(pkColumn))
+				quote(op.Column), quote(tempColumn),
+				quote(op.PKColumn), batchSize,
+				quote(op.PKColumn), quote(op.PKColumn), returning)
 		} else {
-			// Fallback: ctid pagination
 			query = fmt.Sprintf(`
 				UPDATE %s.%s AS t
-				SET %s = t.%s
+				SET %s = (%s)
 				FROM (
 					SELECT ctid
 					FROM %s.%s
@@ -84,9 +322,9 @@ func runMigration(db *sql.DB, schema, table, column, newType string, batchSize i
 				) AS s
 				WHERE t.ctid = s.ctid;
 			`, quote(schema), quote(table),
-				quote(tempColumn), quote(column),
+				quote(tempColumn), op.upExpr(),
 				quote(schema), quote(table),
-				quote(column), quote(tempColumn), batchSize)
+				quote(op.Column), quote(tempColumn), batchSize)
 		}
 
 		if dryRun {
@@ -94,43 +332,166 @@ func runMigration(db *sql.DB, schema, table, column, newType string, batchSize i
 			fmt.Println(query)
 			fmt.Println("  EXPLAIN plan:")
 			explainQuery(db, query)
-			break
+			return nil
 		}
 
 		start := time.Now()
-		res, err := db.Exec(query)
-		checkFatal(err, "Batch update")
+		rows, newLastPK, err := op.runBackfillBatch(db, query, returning != "", rowsDone)
+		if err != nil {
+			return fmt.Errorf("batch update: %w", err)
+		}
+		rowsDone += rows
+		lastPK = newLastPK
 
-		rows, _ := res.RowsAffected()
 		if verbose {
 			fmt.Printf("  ↳ Batch updated %d rows in %s\n", rows, time.Since(start))
 		} else if rows > 0 {
 			fmt.Printf("  ↳ Backfilled %d rows...\n", rows)
 		}
+
+		if hooks.AfterBatch != nil {
+			hooks.AfterBatch(rowsDone, rowsTotal, lastPK)
+		}
 		if rows == 0 {
-			break
+			return nil
+		}
+		if hooks.AfterBatch == nil {
+			time.Sleep(200 * time.Millisecond) // throttle
+		}
+	}
+}
+
+// runBackfillBatch executes one backfill batch. When the operation has a
+// MigrationID, the progress checkpoint (last_pk, rows_done) is written in
+// the same transaction as the batch's UPDATE, so a crash can never leave
+// rows backfilled without the matching progress recorded, or vice versa.
+func (op *ChangeTypeOperation) runBackfillBatch(db *sql.DB, query string, withReturning bool, priorRowsDone int64) (rows int64, lastPK string, err error) {
+	if op.MigrationID == 0 {
+		res, err := db.Exec(query)
+		if err != nil {
+			return 0, "", err
+		}
+		n, _ := res.RowsAffected()
+		return n, "", nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, "", err
+	}
+	defer tx.Rollback()
+
+	if withReturning {
+		rs, err := tx.Query(query)
+		if err != nil {
+			return 0, "", err
+		}
+		for rs.Next() {
+			if err := rs.Scan(&lastPK); err != nil {
+				rs.Close()
+				return 0, "", err
+			}
+			rows++
+		}
+		if err := rs.Err(); err != nil {
+			rs.Close()
+			return 0, "", err
 		}
-		time.Sleep(200 * time.Millisecond) // throttle
+		rs.Close()
+	} else {
+		res, err := tx.Exec(query)
+		if err != nil {
+			return 0, "", err
+		}
+		rows, _ = res.RowsAffected()
+	}
+
+	if err := checkpointProgress(tx, op.MigrationID, lastPK, priorRowsDone+rows); err != nil {
+		return 0, "", err
 	}
 
-	// Step 4: Drop trigger
-	triggerCleanup := fmt.Sprintf(`
-		DROP TRIGGER IF EXISTS %s ON %s.%s;
-		DROP FUNCTION IF EXISTS %s.%s();
-	`, quote(triggerName), quote(schema), quote(table),
-		quote(schema), quote(funcName))
+	return rows, lastPK, tx.Commit()
+}
 
-	execSQLWithOpts(db, triggerCleanup, "Dropping trigger and function", dryRun, verbose)
+// Complete drops the sync mechanism and swaps the temp column into the
+// original column's place, finishing the migration.
+func (op *ChangeTypeOperation) Complete(db *sql.DB, dryRun, verbose bool) error {
+	opts := ExecOpts{DryRun: dryRun, Verbose: verbose}
+	driver := op.driver()
 
-	// Step 5: Swap columns
-	swapSQL := fmt.Sprintf(`
-		ALTER TABLE %s.%s DROP COLUMN %s;
-		ALTER TABLE %s.%s RENAME COLUMN %s TO %s;
-	`, quote(schema), quote(table), quote(column),
-		quote(schema), quote(table), quote(tempColumn), quote(column))
+	if err := driver.DropSyncTrigger(db, op.schema(), op.Table, op.Column, opts); err != nil {
+		return err
+	}
+	if err := driver.SwapColumns(db, op.schema(), op.Table, op.Column, op.tempColumn(), opts); err != nil {
+		return err
+	}
 
-	execSQLWithOpts(db, swapSQL, "Swapping columns", dryRun, verbose)
+	if op.MigrationID != 0 && !dryRun {
+		if err := markMigrationCompleted(db, op.MigrationID); err != nil {
+			return fmt.Errorf("recording migration completion: %w", err)
+		}
+	}
 
 	fmt.Println("Migration completed successfully.")
 	return nil
 }
+
+// Rollback removes the temp column and sync mechanism, leaving the table
+// as it was before Start ran. It is a no-op once Complete has already
+// renamed the temp column into place.
+func (op *ChangeTypeOperation) Rollback(db *sql.DB, dryRun, verbose bool) error {
+	opts := ExecOpts{DryRun: dryRun, Verbose: verbose}
+	driver := op.driver()
+
+	if err := driver.DropSyncTrigger(db, op.schema(), op.Table, op.Column, opts); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`ALTER TABLE %s.%s DROP COLUMN IF EXISTS %s;`,
+		quote(op.schema()), quote(op.Table), quote(op.tempColumn()))
+	return execSQLWithOpts(db, query, "Rolling back column change", dryRun, verbose)
+}
+
+func init() {
+	registerOperation("change_type", func(raw []byte) (Operation, error) {
+		op := &ChangeTypeOperation{}
+		if err := unmarshalOperation(raw, op); err != nil {
+			return nil, err
+		}
+		// A struct-tag/plan-key mismatch decodes silently into a zero-value
+		// field instead of an error, so check the required ones explicitly
+		// rather than letting it surface later as a confusing Postgres error.
+		if op.Table == "" || op.Column == "" || op.NewType == "" {
+			return nil, fmt.Errorf(`change_type operation requires non-empty "table", "column" and "new_type"`)
+		}
+		return op, nil
+	})
+}
+
+// runMigration drives a single column-type change end to end. It is the
+// entry point for the original `-table/-column/-type` CLI flags, and the
+// library entry point for callers that need replication-aware backfills:
+// pass WithSettingsOnMigrationStart, WithKickstartReplication, WithBeforeBatch
+// or WithAfterBatch to opts. Plan files run the same steps through
+// ChangeTypeOperation directly and don't go through these hooks. Every SQL
+// error below comes back as a returned error rather than a process exit,
+// so a caller embedding this package (e.g. to throttle via AfterBatch on
+// replication lag) can react to a failed statement instead of losing the
+// whole process to it. The one exception is the column-existence lookup
+// in columnExists (reached from AddColumn), which still exits the process
+// if its query fails.
+func runMigration(db *sql.DB, op *ChangeTypeOperation, dryRun bool, verbose bool, opts ...Option) error {
+	options := &MigrationOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	op.Options = options
+
+	if err := op.Start(db, dryRun, verbose); err != nil {
+		return err
+	}
+	if err := op.Backfill(db, dryRun, verbose); err != nil {
+		return err
+	}
+	return op.Complete(db, dryRun, verbose)
+}
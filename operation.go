@@ -0,0 +1,39 @@
+package main
+
+import "database/sql"
+
+// Operation is a single step of a migration plan. Each phase maps onto the
+// same start/backfill/complete/rollback lifecycle the CLI already drives for
+// a single column-type change, so a plan can mix several operation kinds and
+// run them back-to-back under one wrapper.
+type Operation interface {
+	// Preflight validates that the operation can run against the current
+	// schema (existence, privileges, type checks) without mutating anything.
+	Preflight(db *sql.DB, verbose bool) error
+	// Start performs the additive, reversible part of the change (e.g.
+	// adding a column, installing a sync trigger).
+	Start(db *sql.DB, dryRun, verbose bool) error
+	// Backfill copies/derives data for rows that existed before Start ran.
+	// Most non-column-type operations have nothing to backfill.
+	Backfill(db *sql.DB, dryRun, verbose bool) error
+	// Complete finalizes the change, dropping anything only needed during
+	// the transition (old columns, sync triggers, temp schemas).
+	Complete(db *sql.DB, dryRun, verbose bool) error
+	// Rollback undoes whatever Start/Backfill did, leaving the schema as it
+	// was found. It is only expected to run before Complete has succeeded.
+	Rollback(db *sql.DB, dryRun, verbose bool) error
+}
+
+// operationBuilder constructs an Operation from the raw JSON payload found
+// under its key in a plan file, e.g. {"change_type": {...}}.
+type operationBuilder func(raw []byte) (Operation, error)
+
+// operationRegistry maps a plan operation key to the builder that knows how
+// to decode it, mirroring the way golang-migrate registers drivers by name.
+var operationRegistry = map[string]operationBuilder{}
+
+// registerOperation makes an operation kind available to plan files under
+// the given JSON key. Operation implementations call this from an init().
+func registerOperation(key string, build operationBuilder) {
+	operationRegistry[key] = build
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// DropColumnOperation removes a column outright. There is nothing to
+// backfill, and dropping is destructive, so Rollback can only warn.
+type DropColumnOperation struct {
+	Schema string
+	Table  string
+	Column string
+}
+
+func (op *DropColumnOperation) schema() string {
+	if op.Schema == "" {
+		return "public"
+	}
+	return op.Schema
+}
+
+// Preflight doesn't fail when the column is already gone: re-running
+// `migrate apply` after a crash in a later plan operation replays every
+// operation from the top, and a drop_column this one already applied
+// should be treated as done rather than block the retry.
+func (op *DropColumnOperation) Preflight(db *sql.DB, verbose bool) error {
+	if !columnExists(db, op.schema(), op.Table, op.Column) {
+		fmt.Printf("→ Column %s already absent on %s.%s; treating drop_column as already applied.\n", op.Column, op.schema(), op.Table)
+	}
+	return nil
+}
+
+func (op *DropColumnOperation) Start(db *sql.DB, dryRun, verbose bool) error {
+	return nil
+}
+
+func (op *DropColumnOperation) Backfill(db *sql.DB, dryRun, verbose bool) error {
+	return nil
+}
+
+func (op *DropColumnOperation) Complete(db *sql.DB, dryRun, verbose bool) error {
+	if !columnExists(db, op.schema(), op.Table, op.Column) {
+		fmt.Printf("→ Column %s already dropped, skipping.\n", op.Column)
+		return nil
+	}
+	query := fmt.Sprintf(`ALTER TABLE %s.%s DROP COLUMN %s;`,
+		quote(op.schema()), quote(op.Table), quote(op.Column))
+	return execSQLWithOpts(db, query, fmt.Sprintf("Dropping column %s", op.Column), dryRun, verbose)
+}
+
+func (op *DropColumnOperation) Rollback(db *sql.DB, dryRun, verbose bool) error {
+	fmt.Printf("→ Cannot undo drop_column for %s.%s; no data was removed before Complete.\n", op.schema(), op.Table)
+	return nil
+}
+
+// AddColumnOperation adds a new column. Start performs the add so that a
+// failed later operation in the same plan can be rolled back by dropping it
+// again.
+type AddColumnOperation struct {
+	Schema  string
+	Table   string
+	Column  string
+	Type    string `json:"new_type"`
+	Default string `json:"default"`
+	NotNull bool   `json:"not_null"`
+}
+
+func (op *AddColumnOperation) schema() string {
+	if op.Schema == "" {
+		return "public"
+	}
+	return op.Schema
+}
+
+// Preflight doesn't fail when the column already exists: re-running
+// `migrate apply` after a crash in a later plan operation replays every
+// operation from the top, and an add_column this one already applied
+// should be treated as done rather than block the retry.
+func (op *AddColumnOperation) Preflight(db *sql.DB, verbose bool) error {
+	if columnExists(db, op.schema(), op.Table, op.Column) {
+		fmt.Printf("→ Column %s already exists on %s.%s; treating add_column as already applied.\n", op.Column, op.schema(), op.Table)
+		return nil
+	}
+	valid, err := typeIsValid(db, op.Type)
+	if err != nil {
+		return fmt.Errorf("type check failed: %w", err)
+	}
+	if !valid {
+		return fmt.Errorf("unrecognized type: %s", op.Type)
+	}
+	return nil
+}
+
+func (op *AddColumnOperation) Start(db *sql.DB, dryRun, verbose bool) error {
+	if columnExists(db, op.schema(), op.Table, op.Column) {
+		fmt.Printf("→ Column %s already exists, skipping add.\n", op.Column)
+		return nil
+	}
+	query := fmt.Sprintf(`ALTER TABLE %s.%s ADD COLUMN %s %s`,
+		quote(op.schema()), quote(op.Table), quote(op.Column), op.Type)
+	if op.Default != "" {
+		query += fmt.Sprintf(" DEFAULT %s", op.Default)
+	}
+	if op.NotNull {
+		query += " NOT NULL"
+	}
+	query += ";"
+	return execSQLWithOpts(db, query, fmt.Sprintf("Adding column %s", op.Column), dryRun, verbose)
+}
+
+func (op *AddColumnOperation) Backfill(db *sql.DB, dryRun, verbose bool) error {
+	return nil
+}
+
+func (op *AddColumnOperation) Complete(db *sql.DB, dryRun, verbose bool) error {
+	return nil
+}
+
+func (op *AddColumnOperation) Rollback(db *sql.DB, dryRun, verbose bool) error {
+	query := fmt.Sprintf(`ALTER TABLE %s.%s DROP COLUMN IF EXISTS %s;`,
+		quote(op.schema()), quote(op.Table), quote(op.Column))
+	return execSQLWithOpts(db, query, fmt.Sprintf("Rolling back add_column %s", op.Column), dryRun, verbose)
+}
+
+// SQLOperation runs a raw SQL statement, with an optional inverse for
+// Rollback. It is the escape hatch for changes the other operation kinds
+// don't model.
+type SQLOperation struct {
+	Up   string `json:"up"`
+	Down string `json:"down"`
+}
+
+func (op *SQLOperation) Preflight(db *sql.DB, verbose bool) error {
+	if op.Up == "" {
+		return fmt.Errorf("sql operation requires a non-empty \"up\"")
+	}
+	return nil
+}
+
+func (op *SQLOperation) Start(db *sql.DB, dryRun, verbose bool) error {
+	return execSQLWithOpts(db, op.Up, "Running plan SQL", dryRun, verbose)
+}
+
+func (op *SQLOperation) Backfill(db *sql.DB, dryRun, verbose bool) error {
+	return nil
+}
+
+func (op *SQLOperation) Complete(db *sql.DB, dryRun, verbose bool) error {
+	return nil
+}
+
+func (op *SQLOperation) Rollback(db *sql.DB, dryRun, verbose bool) error {
+	if op.Down == "" {
+		fmt.Println("→ No \"down\" SQL provided; nothing to roll back.")
+		return nil
+	}
+	return execSQLWithOpts(db, op.Down, "Running plan SQL rollback", dryRun, verbose)
+}
+
+func init() {
+	registerOperation("drop_column", func(raw []byte) (Operation, error) {
+		op := &DropColumnOperation{}
+		if err := unmarshalOperation(raw, op); err != nil {
+			return nil, err
+		}
+		return op, nil
+	})
+	registerOperation("add_column", func(raw []byte) (Operation, error) {
+		op := &AddColumnOperation{}
+		if err := unmarshalOperation(raw, op); err != nil {
+			return nil, err
+		}
+		return op, nil
+	})
+	registerOperation("sql", func(raw []byte) (Operation, error) {
+		op := &SQLOperation{}
+		if err := unmarshalOperation(raw, op); err != nil {
+			return nil, err
+		}
+		return op, nil
+	})
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Plan is a declarative, file-based list of operations to run as one unit,
+// e.g. {"name":"03_widen_ids","operations":[{"change_type":{...}}]}.
+type Plan struct {
+	Name       string            `json:"name"`
+	Operations []json.RawMessage `json:"operations"`
+}
+
+// unmarshalOperation decodes a single plan operation's body (the value
+// under its type key) into dst.
+func unmarshalOperation(raw []byte, dst interface{}) error {
+	return json.Unmarshal(raw, dst)
+}
+
+// LoadPlan reads and decodes a plan file. Only JSON is understood today;
+// the operation registry is what will let a YAML front-end reuse the same
+// Operation implementations later.
+func LoadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading plan file: %w", err)
+	}
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("parsing plan file: %w", err)
+	}
+	if len(plan.Operations) == 0 {
+		return nil, fmt.Errorf("plan %q has no operations", path)
+	}
+	return &plan, nil
+}
+
+// buildOperations decodes each raw plan operation into a concrete Operation
+// using whichever registered key is present in its JSON object. Operations
+// that target a specific warehouse (ChangeTypeOperation) get driver wired
+// in, plus the column_migrate state row resolvePlanMigrationState finds or
+// creates for it, so a crash partway through `migrate apply` can resume the
+// same backfill instead of starting it over; the rest are driver-agnostic
+// DDL and ignore both.
+func buildOperations(db *sql.DB, plan *Plan, driver Driver) ([]Operation, error) {
+	var ops []Operation
+	for i, raw := range plan.Operations {
+		var keyed map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &keyed); err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+		if len(keyed) != 1 {
+			return nil, fmt.Errorf("operation %d: expected exactly one operation type, got %d", i, len(keyed))
+		}
+		for key, body := range keyed {
+			build, ok := operationRegistry[key]
+			if !ok {
+				return nil, fmt.Errorf("operation %d: unknown operation type %q", i, key)
+			}
+			op, err := build(body)
+			if err != nil {
+				return nil, fmt.Errorf("operation %d (%s): %w", i, key, err)
+			}
+			if ct, ok := op.(*ChangeTypeOperation); ok {
+				ct.Driver = driver
+				id, err := resolvePlanMigrationState(db, ct)
+				if err != nil {
+					return nil, fmt.Errorf("operation %d (%s): %w", i, key, err)
+				}
+				ct.MigrationID = id
+			}
+			ops = append(ops, op)
+		}
+	}
+	return ops, nil
+}
+
+// resolvePlanMigrationState wires a plan-driven ChangeTypeOperation into the
+// same column_migrate bookkeeping migrate start/resume uses: an in-progress
+// migration on this column is resumed (reusing its id, and with it its
+// checkpointed backfill progress), otherwise a new one is recorded under a
+// <table>_<column> name, mirroring main.go's default when -name is absent.
+func resolvePlanMigrationState(db *sql.DB, op *ChangeTypeOperation) (int64, error) {
+	active, err := findActiveMigration(db, op.schema(), op.Table, op.Column)
+	if err != nil {
+		return 0, fmt.Errorf("checking for in-progress migration: %w", err)
+	}
+	if active != nil {
+		fmt.Printf("→ Resuming migration %d (%s) on %s.%s.%s, %d rows already done.\n",
+			active.ID, active.Name, op.schema(), op.Table, op.Column, active.RowsDone)
+		return active.ID, nil
+	}
+
+	name := fmt.Sprintf("%s_%s", op.Table, op.Column)
+	id, err := startMigrationState(db, op, name)
+	if err != nil {
+		return 0, fmt.Errorf("recording migration state: %w", err)
+	}
+	return id, nil
+}
+
+// RunPlan executes every operation in the plan in order, running each
+// through Preflight, Start, Backfill and Complete before moving to the
+// next. If a later step of the *current* operation fails, that operation
+// alone is rolled back before the error is returned: earlier operations in
+// the plan already finished their own Complete and are not touched, since
+// Rollback on a completed operation is documented as a no-op (or, for
+// drop_column, outright irreversible) and replaying it would just hide
+// that the plan stopped partway through. A plan that fails after its first
+// operation completes needs a forward fix, not an automatic undo.
+func RunPlan(db *sql.DB, plan *Plan, driver Driver, dryRun, verbose bool) error {
+	if err := ensureStateSchema(db); err != nil {
+		return fmt.Errorf("preparing column_migrate state schema: %w", err)
+	}
+
+	ops, err := buildOperations(db, plan, driver)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Applying plan %q (%d operation(s))...\n", plan.Name, len(ops))
+
+	for i, op := range ops {
+		fmt.Printf("→ [%d/%d] Preflight...\n", i+1, len(ops))
+		if err := op.Preflight(db, verbose); err != nil {
+			return failPlan(db, i, dryRun, verbose, fmt.Errorf("operation %d preflight: %w", i, err))
+		}
+
+		if err := op.Start(db, dryRun, verbose); err != nil {
+			return failPlan(db, i, dryRun, verbose, fmt.Errorf("operation %d start: %w", i, err))
+		}
+
+		if err := op.Backfill(db, dryRun, verbose); err != nil {
+			return rollbackOp(db, op, i, dryRun, verbose, fmt.Errorf("operation %d backfill: %w", i, err))
+		}
+
+		if err := op.Complete(db, dryRun, verbose); err != nil {
+			return rollbackOp(db, op, i, dryRun, verbose, fmt.Errorf("operation %d complete: %w", i, err))
+		}
+	}
+
+	fmt.Printf("Plan %q applied successfully.\n", plan.Name)
+	return nil
+}
+
+// failPlan reports a plan failure that happened in operation i's Preflight
+// or Start. It rolls nothing back, so a Start that failed partway (e.g.
+// after adding a temp column outside a SET LOCAL transaction) leaves what
+// it already applied in place; it only notes that earlier operations in
+// the plan, if any, already completed.
+func failPlan(db *sql.DB, i int, dryRun, verbose bool, cause error) error {
+	fmt.Printf("Plan failed: %v\n", cause)
+	if i > 0 {
+		fmt.Printf("  ↳ %d earlier operation(s) in this plan already completed and were not undone.\n", i)
+	}
+	return cause
+}
+
+// rollbackOp reports a plan failure that happened after operation i's Start
+// succeeded but before its own Complete did, and rolls back that one
+// operation. Earlier operations in the plan are left alone for the same
+// reason failPlan leaves them alone.
+func rollbackOp(db *sql.DB, op Operation, i int, dryRun, verbose bool, cause error) error {
+	fmt.Printf("Plan failed: %v\n", cause)
+	if i > 0 {
+		fmt.Printf("  ↳ %d earlier operation(s) in this plan already completed and were not undone.\n", i)
+	}
+	if err := op.Rollback(db, dryRun, verbose); err != nil {
+		fmt.Printf("  ↳ rollback of operation %d also failed: %v\n", i, err)
+		return cause
+	}
+	// Clear the column_migrate row resolvePlanMigrationState recorded so the
+	// unique active-migration index doesn't wedge future attempts on this
+	// column behind a migration that was actually rolled back.
+	if ct, ok := op.(*ChangeTypeOperation); ok && ct.MigrationID != 0 && !dryRun {
+		if err := markMigrationRolledBack(db, ct.MigrationID); err != nil {
+			fmt.Printf("  ↳ recording rollback of operation %d also failed: %v\n", i, err)
+		}
+	}
+	return cause
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// MigrationState is one row of column_migrate.migrations, joined with its
+// progress row, describing an in-progress or finished column-type change.
+type MigrationState struct {
+	ID        int64
+	Name      string
+	RowsDone  int64
+	RowsTotal sql.NullInt64
+	LastPK    sql.NullString
+}
+
+// ensureStateSchema creates the column_migrate schema and its migrations/
+// progress tables on first use. Only one migration per (schema, table,
+// column) may be active (not completed or rolled back) at a time, enforced
+// by a partial unique index.
+func ensureStateSchema(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE SCHEMA IF NOT EXISTS column_migrate;
+
+		CREATE TABLE IF NOT EXISTS column_migrate.migrations (
+			id             bigserial PRIMARY KEY,
+			name           text NOT NULL,
+			schema_name    text NOT NULL,
+			table_name     text NOT NULL,
+			column_name    text NOT NULL,
+			new_type       text NOT NULL,
+			up             text,
+			down           text,
+			started_at     timestamptz NOT NULL DEFAULT now(),
+			completed_at   timestamptz,
+			rolled_back_at timestamptz,
+			parent_id      bigint REFERENCES column_migrate.migrations(id)
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS migrations_one_active_per_column
+			ON column_migrate.migrations (schema_name, table_name, column_name)
+			WHERE completed_at IS NULL AND rolled_back_at IS NULL;
+
+		CREATE TABLE IF NOT EXISTS column_migrate.progress (
+			migration_id  bigint PRIMARY KEY REFERENCES column_migrate.migrations(id),
+			last_pk       text,
+			rows_done     bigint NOT NULL DEFAULT 0,
+			rows_total    bigint,
+			last_batch_at timestamptz
+		);
+	`)
+	return err
+}
+
+// findActiveMigration returns the in-progress migration for the given
+// column, if any, along with its current backfill progress.
+func findActiveMigration(db *sql.DB, schema, table, column string) (*MigrationState, error) {
+	var s MigrationState
+	err := db.QueryRow(`
+		SELECT m.id, m.name, p.rows_done, p.rows_total, p.last_pk
+		FROM column_migrate.migrations m
+		JOIN column_migrate.progress p ON p.migration_id = m.id
+		WHERE m.schema_name = $1 AND m.table_name = $2 AND m.column_name = $3
+		  AND m.completed_at IS NULL AND m.rolled_back_at IS NULL
+	`, schema, table, column).Scan(&s.ID, &s.Name, &s.RowsDone, &s.RowsTotal, &s.LastPK)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// startMigrationState records a new migration and its (empty) progress row,
+// returning the new migration id.
+func startMigrationState(db *sql.DB, op *ChangeTypeOperation, name string) (int64, error) {
+	var id int64
+	err := db.QueryRow(`
+		INSERT INTO column_migrate.migrations (name, schema_name, table_name, column_name, new_type, up, down)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`, name, op.schema(), op.Table, op.Column, op.NewType, op.Up, op.Down).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	_, err = db.Exec(`INSERT INTO column_migrate.progress (migration_id) VALUES ($1)`, id)
+	return id, err
+}
+
+// checkpointProgress records how far a backfill batch got, in the same
+// transaction as the batch's UPDATE, so a crash mid-backfill can resume
+// from the last committed batch rather than repeating or skipping rows.
+func checkpointProgress(tx *sql.Tx, migrationID int64, lastPK string, rowsDone int64) error {
+	_, err := tx.Exec(`
+		UPDATE column_migrate.progress
+		SET last_pk = NULLIF($2, ''), rows_done = $3, last_batch_at = now()
+		WHERE migration_id = $1
+	`, migrationID, lastPK, rowsDone)
+	return err
+}
+
+// checkpointRowsDoneStandalone records backfill progress for drivers whose
+// BackfillBatch already commits its own transaction (so there is no open
+// *sql.Tx left to share a checkpoint with, unlike the default Postgres
+// path's runBackfillBatch).
+func checkpointRowsDoneStandalone(db *sql.DB, migrationID int64, lastPK string, rowsDone int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := checkpointProgress(tx, migrationID, lastPK, rowsDone); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// markMigrationCompleted marks a migration's Complete step as finished.
+func markMigrationCompleted(db *sql.DB, migrationID int64) error {
+	_, err := db.Exec(`UPDATE column_migrate.migrations SET completed_at = now() WHERE id = $1`, migrationID)
+	return err
+}
+
+// markMigrationRolledBack marks a migration as aborted/rolled back.
+func markMigrationRolledBack(db *sql.DB, migrationID int64) error {
+	_, err := db.Exec(`UPDATE column_migrate.migrations SET rolled_back_at = now() WHERE id = $1`, migrationID)
+	return err
+}
+
+// resolveMigration looks up any active migration for op's column and
+// applies --resume/--abort semantics, returning the migration id to use
+// and whether the caller should go on (false e.g. after handling --abort,
+// when the id is that of the aborted migration, or 0 if none was found).
+// The --abort
+// path mirrors `migrate rollback` exactly (same view/schema cleanup, same
+// dryRun/verbose handling) so aborting from `migrate start -abort` doesn't
+// leave the colmigrate_v<name> schema and view behind the way the bare
+// column/trigger rollback alone would.
+func resolveMigration(db *sql.DB, op *ChangeTypeOperation, name string, resume, abort, dryRun, verbose bool) (id int64, shouldContinue bool, err error) {
+	if err := ensureStateSchema(db); err != nil {
+		return 0, false, fmt.Errorf("preparing column_migrate state schema: %w", err)
+	}
+
+	active, err := findActiveMigration(db, op.schema(), op.Table, op.Column)
+	if err != nil {
+		return 0, false, fmt.Errorf("checking for in-progress migration: %w", err)
+	}
+
+	if active == nil {
+		if abort {
+			fmt.Println("No in-progress migration found to abort.")
+			return 0, false, nil
+		}
+		id, err := startMigrationState(db, op, name)
+		if err != nil {
+			return 0, false, fmt.Errorf("recording migration state: %w", err)
+		}
+		return id, true, nil
+	}
+
+	if abort {
+		fmt.Printf("Aborting in-progress migration %d (%s)...\n", active.ID, active.Name)
+		op.MigrationID = active.ID
+		// Use the migration's own recorded name, not the caller-supplied
+		// one: -name is optional and defaults to <table>_<column>, so an
+		// omitted or mismatched flag here must not make cleanup target the
+		// wrong colmigrate_v<name> schema and silently orphan the real one.
+		if err := dropVersionedView(db, op.Table, active.Name, dryRun, verbose); err != nil {
+			return 0, false, err
+		}
+		if err := op.Rollback(db, dryRun, verbose); err != nil {
+			return 0, false, err
+		}
+		if err := dropVersionedSchema(db, active.Name, dryRun, verbose); err != nil {
+			return 0, false, err
+		}
+		if dryRun {
+			return active.ID, false, nil
+		}
+		return active.ID, false, markMigrationRolledBack(db, active.ID)
+	}
+
+	if !resume {
+		return 0, false, fmt.Errorf(
+			"migration %d (%s) is already in progress on %s.%s.%s (%d rows done); pass -resume or -abort",
+			active.ID, active.Name, op.schema(), op.Table, op.Column, active.RowsDone)
+	}
+
+	fmt.Printf("Resuming migration %d (%s), %d rows already done", active.ID, active.Name, active.RowsDone)
+	if active.LastPK.Valid {
+		fmt.Printf(", last pk %s", active.LastPK.String)
+	}
+	fmt.Println("...")
+	return active.ID, true, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// versionedSchema returns the name of the Postgres schema that holds the
+// versioned view for a migration, e.g. "colmigrate_v03_widen_ids".
+func versionedSchema(name string) string {
+	return "colmigrate_v" + name
+}
+
+// createVersionedView creates (or replaces) a view under the migration's
+// versioned schema that exposes the temp column under the original column's
+// name, so new readers can `SET search_path TO colmigrate_v<name>, public`
+// and see the new type while old readers keep using public.<table> with
+// the original type untouched.
+func createVersionedView(db *sql.DB, schema, table, column, tempColumn, newType, name string, dryRun, verbose bool) error {
+	viewSchema := versionedSchema(name)
+
+	if err := execSQLWithOpts(db, fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS %s;`, quote(viewSchema)),
+		"Creating versioned schema", dryRun, verbose); err != nil {
+		return err
+	}
+
+	cols, err := tableColumns(db, schema, table)
+	if err != nil {
+		return fmt.Errorf("listing columns of %s.%s: %w", schema, table, err)
+	}
+	if len(cols) == 0 {
+		return fmt.Errorf("no columns found on %s.%s", schema, table)
+	}
+
+	var exprs []string
+	for _, c := range cols {
+		switch c.Name {
+		case tempColumn:
+			// The temp column itself isn't part of the old-shaped row.
+			continue
+		case column:
+			exprs = append(exprs, fmt.Sprintf("%s::%s AS %s", quote(tempColumn), newType, quote(column)))
+		default:
+			exprs = append(exprs, fmt.Sprintf("%s::%s AS %s", quote(c.Name), c.Type, quote(c.Name)))
+		}
+	}
+
+	viewSQL := fmt.Sprintf(`
+		CREATE OR REPLACE VIEW %s.%s AS
+		SELECT
+			%s
+		FROM %s.%s;
+	`, quote(viewSchema), quote(table), strings.Join(exprs, ",\n\t\t\t"), quote(schema), quote(table))
+
+	return execSQLWithOpts(db, viewSQL, fmt.Sprintf("Creating versioned view %s.%s", viewSchema, table), dryRun, verbose)
+}
+
+// dropVersionedView drops the view for a migration but leaves its schema
+// in place (complete and rollback each drop the schema separately via
+// dropVersionedSchema).
+func dropVersionedView(db *sql.DB, table, name string, dryRun, verbose bool) error {
+	viewSchema := versionedSchema(name)
+	query := fmt.Sprintf(`DROP VIEW IF EXISTS %s.%s;`, quote(viewSchema), quote(table))
+	return execSQLWithOpts(db, query, fmt.Sprintf("Dropping versioned view %s.%s", viewSchema, table), dryRun, verbose)
+}
+
+// dropVersionedSchema removes the migration's versioned schema entirely,
+// once no readers should need it any more (after complete or rollback).
+func dropVersionedSchema(db *sql.DB, name string, dryRun, verbose bool) error {
+	query := fmt.Sprintf(`DROP SCHEMA IF EXISTS %s CASCADE;`, quote(versionedSchema(name)))
+	return execSQLWithOpts(db, query, fmt.Sprintf("Dropping versioned schema %s", versionedSchema(name)), dryRun, verbose)
+}